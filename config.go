@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadTableConfig reads a ResTableConfig from the current position of sr.
+// The struct has grown new fields across Android releases, so only the
+// first Size bytes (the value the chunk actually stored) are read off
+// disk; any fields beyond that default to zero rather than reading
+// garbage from whatever follows. The reader is left positioned right
+// after the Size bytes, matching how binary.Read would have left it.
+func ReadTableConfig(sr *io.SectionReader) (*ResTableConfig, error) {
+	pos, err := sr.Seek(0, os.SEEK_CUR)
+	if err != nil {
+		return nil, err
+	}
+
+	var size uint32
+	if err := binary.Read(sr, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, size)
+	if _, err := sr.Seek(pos, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(sr, raw); err != nil {
+		return nil, err
+	}
+
+	full := make([]byte, binary.Size(ResTableConfig{}))
+	copy(full, raw)
+
+	config := new(ResTableConfig)
+	if err := binary.Read(bytes.NewReader(full), binary.LittleEndian, config); err != nil {
+		return nil, err
+	}
+	config.Size = size
+
+	if _, err := sr.Seek(pos+int64(size), os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Layout direction, part of ScreenConfig's screenLayout byte (bits 6-7).
+const (
+	MASK_LAYOUTDIR = 0xC0
+	LDLTR          = 0x40
+	LDRTL          = 0x80
+)
+
+// Screen size bucket, part of the screenLayout byte (bits 0-3).
+const (
+	MASK_SCREENSIZE   = 0x0F
+	SCREENSIZE_SMALL  = 0x01
+	SCREENSIZE_NORMAL = 0x02
+	SCREENSIZE_LARGE  = 0x03
+	SCREENSIZE_XLARGE = 0x04
+)
+
+// Screen "long" bit, part of the screenLayout byte (bits 4-5).
+const (
+	MASK_SCREENLONG = 0x30
+	SCREENLONG_NO   = 0x10
+	SCREENLONG_YES  = 0x20
+)
+
+// Screen "round" bit, part of ScreenConfig2's screenLayout2 byte (bits 0-1).
+const (
+	MASK_SCREENROUND = 0x03
+	SCREENROUND_NO   = 0x01
+	SCREENROUND_YES  = 0x02
+)
+
+func (c *ResTableConfig) mcc() uint16 { return uint16(c.Imsi) }
+func (c *ResTableConfig) mnc() uint16 { return uint16(c.Imsi >> 16) }
+
+func (c *ResTableConfig) orientation() uint8 { return uint8(c.ScreenType) }
+func (c *ResTableConfig) touchscreen() uint8 { return uint8(c.ScreenType >> 8) }
+func (c *ResTableConfig) density() uint16    { return uint16(c.ScreenType >> 16) }
+
+func (c *ResTableConfig) keyboard() uint8   { return uint8(c.Input) }
+func (c *ResTableConfig) navigation() uint8 { return uint8(c.Input >> 8) }
+func (c *ResTableConfig) inputFlags() uint8 { return uint8(c.Input >> 16) }
+
+func (c *ResTableConfig) screenWidth() uint16  { return uint16(c.ScreenSize) }
+func (c *ResTableConfig) screenHeight() uint16 { return uint16(c.ScreenSize >> 16) }
+
+func (c *ResTableConfig) sdkVersion() uint16 { return uint16(c.Version) }
+
+func (c *ResTableConfig) screenLayout() uint8           { return uint8(c.ScreenConfig) }
+func (c *ResTableConfig) uiMode() uint8                 { return uint8(c.ScreenConfig >> 8) }
+func (c *ResTableConfig) smallestScreenWidthDp() uint16 { return uint16(c.ScreenConfig >> 16) }
+
+func (c *ResTableConfig) screenWidthDp() uint16  { return uint16(c.ScreenSizeDp) }
+func (c *ResTableConfig) screenHeightDp() uint16 { return uint16(c.ScreenSizeDp >> 16) }
+
+// screenLayout2 is ScreenConfig2's low byte: screen round (bits 0-1),
+// color mode/wide gamut/HDR (bits 2-5).
+func (c *ResTableConfig) screenLayout2() uint8 { return uint8(c.ScreenConfig2) }
+
+// isMatch reports whether c is a candidate for desired at all: every
+// qualifier c specifies must either be unset in desired or must equal
+// the value desired asked for (screen size, smallest width and sdk
+// version: must not exceed it). An empty/zero qualifier on c always
+// matches, since it means "any". Density, screen width/height in dp and
+// screen dimensions are not checked here.
+func (c *ResTableConfig) isMatch(desired *ResTableConfig) bool {
+	if c.mcc() != 0 && desired.mcc() != 0 && c.mcc() != desired.mcc() {
+		return false
+	}
+	if c.mnc() != 0 && desired.mnc() != 0 && c.mnc() != desired.mnc() {
+		return false
+	}
+	if c.Locale.Language != [2]uint8{0, 0} && desired.Locale.Language != [2]uint8{0, 0} &&
+		c.Locale.Language != desired.Locale.Language {
+		return false
+	}
+	if c.Locale.Country != [2]uint8{0, 0} && desired.Locale.Country != [2]uint8{0, 0} &&
+		c.Locale.Country != desired.Locale.Country {
+		return false
+	}
+	if c.LocaleScript != [4]uint8{} && desired.LocaleScript != [4]uint8{} &&
+		c.LocaleScript != desired.LocaleScript {
+		return false
+	}
+	if dir := c.screenLayout() & MASK_LAYOUTDIR; dir != 0 {
+		if ddir := desired.screenLayout() & MASK_LAYOUTDIR; ddir != 0 && dir != ddir {
+			return false
+		}
+	}
+	if size := c.screenLayout() & MASK_SCREENSIZE; size != 0 {
+		if dsize := desired.screenLayout() & MASK_SCREENSIZE; dsize != 0 && size > dsize {
+			return false
+		}
+	}
+	if long := c.screenLayout() & MASK_SCREENLONG; long != 0 {
+		if dlong := desired.screenLayout() & MASK_SCREENLONG; dlong != 0 && long != dlong {
+			return false
+		}
+	}
+	if round := c.screenLayout2() & MASK_SCREENROUND; round != 0 {
+		if dround := desired.screenLayout2() & MASK_SCREENROUND; dround != 0 && round != dround {
+			return false
+		}
+	}
+	if c.smallestScreenWidthDp() != 0 && desired.smallestScreenWidthDp() != 0 &&
+		c.smallestScreenWidthDp() > desired.smallestScreenWidthDp() {
+		return false
+	}
+	if c.orientation() != 0 && desired.orientation() != 0 && c.orientation() != desired.orientation() {
+		return false
+	}
+	if c.uiMode() != 0 && desired.uiMode() != 0 && c.uiMode() != desired.uiMode() {
+		return false
+	}
+	if c.touchscreen() != 0 && desired.touchscreen() != 0 && c.touchscreen() != desired.touchscreen() {
+		return false
+	}
+	if c.keyboard() != 0 && desired.keyboard() != 0 && c.keyboard() != desired.keyboard() {
+		return false
+	}
+	if c.navigation() != 0 && desired.navigation() != 0 && c.navigation() != desired.navigation() {
+		return false
+	}
+	if c.sdkVersion() != 0 && desired.sdkVersion() != 0 && c.sdkVersion() > desired.sdkVersion() {
+		return false
+	}
+	return true
+}
+
+// isMoreSpecificThan reports whether c is a better match for desired than
+// other is, walking axes in AOSP's precedence order (mcc -> mnc -> locale
+// (language, then region) -> layout direction -> smallest width -> screen
+// width/height in dp -> screen size -> screen long/round
+// -> orientation -> ui mode -> density -> touchscreen -> keyboard ->
+// navigation -> screen dimensions -> sdk version) and returning at the
+// first axis that distinguishes them.
+func (c *ResTableConfig) isMoreSpecificThan(other *ResTableConfig, desired *ResTableConfig) bool {
+	if (c.mcc() != 0) != (other.mcc() != 0) {
+		return c.mcc() != 0
+	}
+	if (c.mnc() != 0) != (other.mnc() != 0) {
+		return c.mnc() != 0
+	}
+	cLocale := c.Locale.Language != [2]uint8{0, 0} || c.Locale.Country != [2]uint8{0, 0}
+	oLocale := other.Locale.Language != [2]uint8{0, 0} || other.Locale.Country != [2]uint8{0, 0}
+	if cLocale != oLocale {
+		return cLocale
+	}
+	cRegion, oRegion := c.Locale.Country != [2]uint8{0, 0}, other.Locale.Country != [2]uint8{0, 0}
+	if cRegion != oRegion {
+		return cRegion
+	}
+	cDir, oDir := c.screenLayout()&MASK_LAYOUTDIR, other.screenLayout()&MASK_LAYOUTDIR
+	if (cDir != 0) != (oDir != 0) {
+		return cDir != 0
+	}
+	if (c.smallestScreenWidthDp() != 0) != (other.smallestScreenWidthDp() != 0) {
+		return c.smallestScreenWidthDp() != 0
+	}
+	if c.smallestScreenWidthDp() != other.smallestScreenWidthDp() {
+		return c.smallestScreenWidthDp() > other.smallestScreenWidthDp()
+	}
+	cDp := c.screenWidthDp() != 0 || c.screenHeightDp() != 0
+	oDp := other.screenWidthDp() != 0 || other.screenHeightDp() != 0
+	if cDp != oDp {
+		return cDp
+	}
+	cSize, oSize := c.screenLayout()&MASK_SCREENSIZE, other.screenLayout()&MASK_SCREENSIZE
+	if cSize != oSize {
+		return cSize > oSize
+	}
+	cLong, oLong := c.screenLayout()&MASK_SCREENLONG, other.screenLayout()&MASK_SCREENLONG
+	if (cLong != 0) != (oLong != 0) {
+		return cLong != 0
+	}
+	cRound, oRound := c.screenLayout2()&MASK_SCREENROUND, other.screenLayout2()&MASK_SCREENROUND
+	if (cRound != 0) != (oRound != 0) {
+		return cRound != 0
+	}
+	if (c.orientation() != 0) != (other.orientation() != 0) {
+		return c.orientation() != 0
+	}
+	if (c.uiMode() != 0) != (other.uiMode() != 0) {
+		return c.uiMode() != 0
+	}
+	if c.density() != other.density() {
+		return closerDensity(c.density(), other.density(), desired.density())
+	}
+	if (c.touchscreen() != 0) != (other.touchscreen() != 0) {
+		return c.touchscreen() != 0
+	}
+	if (c.keyboard() != 0) != (other.keyboard() != 0) {
+		return c.keyboard() != 0
+	}
+	if (c.navigation() != 0) != (other.navigation() != 0) {
+		return c.navigation() != 0
+	}
+	if (c.screenWidth() != 0 || c.screenHeight() != 0) != (other.screenWidth() != 0 || other.screenHeight() != 0) {
+		return c.screenWidth() != 0 || c.screenHeight() != 0
+	}
+	if (c.sdkVersion() != 0) != (other.sdkVersion() != 0) {
+		return c.sdkVersion() != 0
+	}
+	return c.sdkVersion() > other.sdkVersion()
+}
+
+// closerDensity picks whichever of a/b is the better density match for
+// desired: AOSP prefers the smallest density >= desired, falling back to
+// the largest density below it only if nothing qualifies at or above.
+func closerDensity(a, b, desired uint16) bool {
+	if desired == 0 {
+		return a > b
+	}
+	aOK, bOK := a >= desired, b >= desired
+	if aOK != bOK {
+		return aOK
+	}
+	if aOK {
+		return a < b
+	}
+	return a > b
+}
+
+// bestMatch picks the TableType among candidates that best matches
+// desired, per AOSP's resource resolution algorithm. It returns nil if
+// none of the candidates are compatible with desired at all.
+func bestMatch(candidates []*TableType, desired *ResTableConfig) *TableType {
+	if desired == nil {
+		desired = &ResTableConfig{}
+	}
+	var best *TableType
+	for _, c := range candidates {
+		if !c.Header.Config.isMatch(desired) {
+			continue
+		}
+		if best == nil || c.Header.Config.isMoreSpecificThan(&best.Header.Config, desired) {
+			best = c
+		}
+	}
+	return best
+}
+
+// densityQualifiers maps the qualifier strings ParseConfig accepts to
+// their density value in dpi, per AOSP's DENSITY_* constants.
+var densityQualifiers = map[string]uint16{
+	"nodpi": 0, "ldpi": 120, "mdpi": 160, "tvdpi": 213,
+	"hdpi": 240, "xhdpi": 320, "xxhdpi": 480, "xxxhdpi": 640,
+	"anydpi": 0xFFFE,
+}
+
+// ParseConfig parses an aapt-style resource qualifier string, e.g.
+// "en-rUS-xxhdpi-v26", "zh-rCN-ldrtl-w600dp", or the BCP-47 form
+// "b+sr+Latn+RS", into a ResTableConfig suitable for passing to bestMatch.
+//
+// Region is only ever stored as the 2-letter form ResTableConfig.Locale.Country
+// has room for: a BCP-47 UN M.49 numeric region (e.g. "b+es+419") is accepted
+// but dropped, since there's nowhere to put it.
+func ParseConfig(qualifiers string) (*ResTableConfig, error) {
+	config := &ResTableConfig{Size: uint32(binary.Size(ResTableConfig{}))}
+	if qualifiers == "" {
+		return config, nil
+	}
+
+	for _, part := range strings.Split(qualifiers, "-") {
+		switch {
+		case part == "":
+			continue
+		case part == "ldltr":
+			config.ScreenConfig = (config.ScreenConfig &^ MASK_LAYOUTDIR) | LDLTR
+		case part == "ldrtl":
+			config.ScreenConfig = (config.ScreenConfig &^ MASK_LAYOUTDIR) | LDRTL
+		case part == "long":
+			config.ScreenConfig = (config.ScreenConfig &^ MASK_SCREENLONG) | SCREENLONG_YES
+		case part == "notlong":
+			config.ScreenConfig = (config.ScreenConfig &^ MASK_SCREENLONG) | SCREENLONG_NO
+		case part == "round":
+			config.ScreenConfig2 = (config.ScreenConfig2 &^ MASK_SCREENROUND) | SCREENROUND_YES
+		case part == "notround":
+			config.ScreenConfig2 = (config.ScreenConfig2 &^ MASK_SCREENROUND) | SCREENROUND_NO
+		case strings.HasPrefix(part, "b+"):
+			lang, script, region, err := parseBCP47(part)
+			if err != nil {
+				return nil, err
+			}
+			if len(lang) == 2 {
+				config.Locale.Language = [2]uint8{lang[0], lang[1]}
+			}
+			if len(region) == 2 {
+				config.Locale.Country = [2]uint8{region[0], region[1]}
+			}
+			if len(script) == 4 {
+				copy(config.LocaleScript[:], script)
+			}
+		case len(part) == 2 && isLowerAlpha(part):
+			config.Locale.Language = [2]uint8{part[0], part[1]}
+		case len(part) == 3 && part[0] == 'r' && isUpperAlpha(part[1:]):
+			config.Locale.Country = [2]uint8{part[1], part[2]}
+		case densityOK(part):
+			config.ScreenType = (config.ScreenType &^ (0xFFFF << 16)) | uint32(densityQualifiers[part])<<16
+		case len(part) > 1 && part[0] == 'v':
+			if v, err := strconv.Atoi(part[1:]); err == nil {
+				config.Version = (config.Version &^ 0xFFFF) | uint32(uint16(v))
+			} else {
+				return nil, fmt.Errorf("androidbinary: invalid sdk version qualifier %q", part)
+			}
+		case len(part) > 2 && part[0] == 's' && part[1] == 'w' && strings.HasSuffix(part, "dp"):
+			if v, err := strconv.Atoi(part[2 : len(part)-2]); err == nil {
+				config.ScreenConfig = (config.ScreenConfig &^ (0xFFFF << 16)) | uint32(uint16(v))<<16
+			} else {
+				return nil, fmt.Errorf("androidbinary: invalid smallest-width qualifier %q", part)
+			}
+		case len(part) > 1 && part[0] == 'w' && strings.HasSuffix(part, "dp"):
+			if v, err := strconv.Atoi(part[1 : len(part)-2]); err == nil {
+				config.ScreenSizeDp = (config.ScreenSizeDp &^ 0xFFFF) | uint32(uint16(v))
+			} else {
+				return nil, fmt.Errorf("androidbinary: invalid screen width qualifier %q", part)
+			}
+		default:
+			return nil, fmt.Errorf("androidbinary: unsupported qualifier %q", part)
+		}
+	}
+
+	// Pseudolocales (en-XA, ar-XB) force a layout direction independent of
+	// the usual per-language default, the same way AOSP's
+	// ResTable_config::isPseudoLocale does, unless the qualifier string
+	// already set one explicitly.
+	if rtl, ok := isPseudoLocale(config.Locale.Language, config.Locale.Country); ok &&
+		config.ScreenConfig&MASK_LAYOUTDIR == 0 {
+		if rtl {
+			config.ScreenConfig |= LDRTL
+		} else {
+			config.ScreenConfig |= LDLTR
+		}
+	}
+
+	return config, nil
+}
+
+// parseBCP47 parses a "b+lang[+Script][+REGION]" qualifier (the form
+// ParseConfig accepts for locales that need a script subtag, e.g.
+// "b+sr+Latn+RS") into its language/script/region components.
+func parseBCP47(part string) (lang, script, region string, err error) {
+	tags := strings.Split(strings.TrimPrefix(part, "b+"), "+")
+	if len(tags[0]) != 2 || !isAlpha(tags[0]) {
+		return "", "", "", fmt.Errorf("androidbinary: invalid BCP-47 qualifier %q", part)
+	}
+	lang = strings.ToLower(tags[0])
+	for _, tag := range tags[1:] {
+		switch {
+		case len(tag) == 4 && isAlpha(tag):
+			script = strings.ToUpper(tag[:1]) + strings.ToLower(tag[1:])
+		case len(tag) == 2 && isAlpha(tag):
+			region = strings.ToUpper(tag)
+		case len(tag) == 3 && isDigits(tag):
+			// UN M.49 numeric region code; accepted but dropped, see ParseConfig.
+		default:
+			return "", "", "", fmt.Errorf("androidbinary: invalid BCP-47 subtag %q in %q", tag, part)
+		}
+	}
+	return lang, script, region, nil
+}
+
+// isPseudoLocale reports whether language+country name one of Android's
+// pseudolocales, used to test accented-Latin (en-XA) and RTL (ar-XB)
+// layouts without a real translation, and whether that pseudolocale forces
+// RTL layout direction.
+func isPseudoLocale(language, country [2]uint8) (rtl bool, ok bool) {
+	switch {
+	case language == [2]uint8{'e', 'n'} && country == [2]uint8{'X', 'A'}:
+		return false, true
+	case language == [2]uint8{'a', 'r'} && country == [2]uint8{'X', 'B'}:
+		return true, true
+	}
+	return false, false
+}
+
+func isLowerAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUpperAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlpha reports whether s is entirely ASCII letters, in either case.
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func densityOK(s string) bool {
+	_, ok := densityQualifiers[s]
+	return ok
+}
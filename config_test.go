@@ -0,0 +1,210 @@
+package main
+
+import "testing"
+
+func TestParseConfigQualifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		quals string
+		check func(t *testing.T, c *ResTableConfig)
+	}{
+		{"bare language", "en", func(t *testing.T, c *ResTableConfig) {
+			if c.Locale.Language != [2]uint8{'e', 'n'} {
+				t.Errorf("Language = %v, want en", c.Locale.Language)
+			}
+		}},
+		{"language+region", "en-rUS", func(t *testing.T, c *ResTableConfig) {
+			if c.Locale.Language != [2]uint8{'e', 'n'} || c.Locale.Country != [2]uint8{'U', 'S'} {
+				t.Errorf("Locale = %+v, want en-US", c.Locale)
+			}
+		}},
+		{"BCP-47 script+region", "b+sr+Latn+RS", func(t *testing.T, c *ResTableConfig) {
+			if c.Locale.Language != [2]uint8{'s', 'r'} || c.Locale.Country != [2]uint8{'R', 'S'} {
+				t.Errorf("Locale = %+v, want sr-RS", c.Locale)
+			}
+			if c.LocaleScript != [4]uint8{'L', 'a', 't', 'n'} {
+				t.Errorf("LocaleScript = %s, want Latn", c.LocaleScript)
+			}
+		}},
+		{"pseudolocale en-XA forces LTR", "en-rXA", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_LAYOUTDIR != LDLTR {
+				t.Errorf("screenLayout dir = 0x%x, want LDLTR", c.screenLayout()&MASK_LAYOUTDIR)
+			}
+		}},
+		{"pseudolocale ar-XB forces RTL", "ar-rXB", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_LAYOUTDIR != LDRTL {
+				t.Errorf("screenLayout dir = 0x%x, want LDRTL", c.screenLayout()&MASK_LAYOUTDIR)
+			}
+		}},
+		{"explicit direction overrides pseudolocale default", "ar-rXB-ldltr", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_LAYOUTDIR != LDLTR {
+				t.Errorf("screenLayout dir = 0x%x, want LDLTR (explicit qualifier should win)", c.screenLayout()&MASK_LAYOUTDIR)
+			}
+		}},
+		{"density", "xxhdpi", func(t *testing.T, c *ResTableConfig) {
+			if c.density() != 480 {
+				t.Errorf("density = %d, want 480", c.density())
+			}
+		}},
+		{"sdk version", "v26", func(t *testing.T, c *ResTableConfig) {
+			if c.sdkVersion() != 26 {
+				t.Errorf("sdkVersion = %d, want 26", c.sdkVersion())
+			}
+		}},
+		{"screen width", "w600dp", func(t *testing.T, c *ResTableConfig) {
+			if c.screenWidthDp() != 600 {
+				t.Errorf("screenWidthDp = %d, want 600", c.screenWidthDp())
+			}
+			if c.smallestScreenWidthDp() != 0 {
+				t.Errorf("smallestScreenWidthDp = %d, want 0 (w600dp must not set sw)", c.smallestScreenWidthDp())
+			}
+		}},
+		{"smallest width", "sw600dp", func(t *testing.T, c *ResTableConfig) {
+			if c.smallestScreenWidthDp() != 600 {
+				t.Errorf("smallestScreenWidthDp = %d, want 600", c.smallestScreenWidthDp())
+			}
+			if c.screenWidthDp() != 0 {
+				t.Errorf("screenWidthDp = %d, want 0 (sw600dp must not set w)", c.screenWidthDp())
+			}
+		}},
+		{"layout direction", "ldrtl", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_LAYOUTDIR != LDRTL {
+				t.Errorf("screenLayout dir = 0x%x, want LDRTL", c.screenLayout()&MASK_LAYOUTDIR)
+			}
+		}},
+		{"long", "long", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_SCREENLONG != SCREENLONG_YES {
+				t.Errorf("screenLayout long = 0x%x, want SCREENLONG_YES", c.screenLayout()&MASK_SCREENLONG)
+			}
+		}},
+		{"notlong", "notlong", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout()&MASK_SCREENLONG != SCREENLONG_NO {
+				t.Errorf("screenLayout long = 0x%x, want SCREENLONG_NO", c.screenLayout()&MASK_SCREENLONG)
+			}
+		}},
+		{"round", "round", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout2()&MASK_SCREENROUND != SCREENROUND_YES {
+				t.Errorf("screenLayout2 round = 0x%x, want SCREENROUND_YES", c.screenLayout2()&MASK_SCREENROUND)
+			}
+		}},
+		{"notround", "notround", func(t *testing.T, c *ResTableConfig) {
+			if c.screenLayout2()&MASK_SCREENROUND != SCREENROUND_NO {
+				t.Errorf("screenLayout2 round = 0x%x, want SCREENROUND_NO", c.screenLayout2()&MASK_SCREENROUND)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseConfig(tt.quals)
+			if err != nil {
+				t.Fatalf("ParseConfig(%q): %v", tt.quals, err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+func TestParseConfigRejectsUnknownQualifier(t *testing.T) {
+	if _, err := ParseConfig("bogus-qualifier"); err == nil {
+		t.Error("ParseConfig accepted an unsupported qualifier, want error")
+	}
+}
+
+// mustParseConfig is a test helper: the config strings used as bestMatch
+// fixtures below are all valid by construction, so a parse failure is a
+// test bug and stops the test at once via t.Fatalf.
+func mustParseConfig(t *testing.T, quals string) *ResTableConfig {
+	t.Helper()
+	c, err := ParseConfig(quals)
+	if err != nil {
+		t.Fatalf("ParseConfig(%q): %v", quals, err)
+	}
+	return c
+}
+
+func bestMatchQualifiers(t *testing.T, desired string, candidates ...string) string {
+	t.Helper()
+	var types []*TableType
+	byConfig := map[*TableType]string{}
+	for _, q := range candidates {
+		tt := &TableType{Header: &ResTableType{Config: *mustParseConfig(t, q)}}
+		types = append(types, tt)
+		byConfig[tt] = q
+	}
+	best := bestMatch(types, mustParseConfig(t, desired))
+	if best == nil {
+		return ""
+	}
+	return byConfig[best]
+}
+
+func TestBestMatchPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		desired    string
+		candidates []string
+		want       string
+	}{
+		{"locale: language match beats unset", "en", []string{"en", ""}, "en"},
+		{"locale: specific beats generic", "en-rUS", []string{"en-rUS", "en", ""}, "en-rUS"},
+		{"layout direction: matching beats unset", "ar-rXB", []string{"ldrtl", "ldltr", ""}, "ldrtl"},
+		{"smallest width: closest without exceeding desired", "sw700dp", []string{"sw600dp", "sw300dp", ""}, "sw600dp"},
+		{"smallest width: candidate larger than desired excluded", "sw300dp", []string{"sw600dp", "sw300dp"}, "sw300dp"},
+		{"screen width: present beats unset", "w600dp", []string{"w600dp", ""}, "w600dp"},
+		{"screen long: matching beats unset", "long", []string{"long", "notlong", ""}, "long"},
+		{"screen round: matching beats unset", "round", []string{"round", "notround", ""}, "round"},
+		{"density: nearest match at or above desired wins", "xhdpi", []string{"mdpi", "xxhdpi", "hdpi"}, "xxhdpi"},
+		{"density: falls back to largest below when none at or above", "xxxhdpi", []string{"mdpi", "hdpi"}, "hdpi"},
+		{"sdk version: highest not exceeding desired wins", "v26", []string{"v21", "v26", "v28"}, "v26"},
+		{"sdk version: candidate above desired excluded", "v21", []string{"v26"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bestMatchQualifiers(t, tt.desired, tt.candidates...); got != tt.want {
+				t.Errorf("bestMatch(%q, %v) = %q, want %q", tt.desired, tt.candidates, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestMatchTouchscreen(t *testing.T) {
+	desired := &ResTableConfig{
+		Input: uint32(1) | uint32(1)<<8, // keyboard=1, navigation=1
+	}
+	desired.ScreenType = uint32(1) << 8 // touchscreen=1
+	finger := &TableType{Header: &ResTableType{Config: ResTableConfig{ScreenType: uint32(1) << 8}}}
+	none := &TableType{Header: &ResTableType{Config: ResTableConfig{}}}
+	best := bestMatch([]*TableType{none, finger}, desired)
+	if best != finger {
+		t.Errorf("bestMatch picked the unset-touchscreen candidate, want the matching one")
+	}
+}
+
+func TestBestMatchScreenSizeBucket(t *testing.T) {
+	desired := &ResTableConfig{ScreenConfig: SCREENSIZE_XLARGE}
+	small := &TableType{Header: &ResTableType{Config: ResTableConfig{ScreenConfig: SCREENSIZE_SMALL}}}
+	large := &TableType{Header: &ResTableType{Config: ResTableConfig{ScreenConfig: SCREENSIZE_LARGE}}}
+	best := bestMatch([]*TableType{small, large}, desired)
+	if best != large {
+		t.Errorf("bestMatch picked the smaller screen size bucket, want the larger one")
+	}
+}
+
+func TestBestMatchOrientation(t *testing.T) {
+	desired := &ResTableConfig{ScreenType: 1} // orientation=1
+	portrait := &TableType{Header: &ResTableType{Config: ResTableConfig{ScreenType: 1}}}
+	unset := &TableType{Header: &ResTableType{Config: ResTableConfig{}}}
+	best := bestMatch([]*TableType{unset, portrait}, desired)
+	if best != portrait {
+		t.Errorf("bestMatch picked the unset-orientation candidate, want the matching one")
+	}
+}
+
+func TestBestMatchReturnsNilWhenNoneCompatible(t *testing.T) {
+	desired := mustParseConfig(t, "v10")
+	candidate := mustParseConfig(t, "v26")
+	tt := &TableType{Header: &ResTableType{Config: *candidate}}
+	if best := bestMatch([]*TableType{tt}, desired); best != nil {
+		t.Errorf("bestMatch = %+v, want nil (candidate requires a higher sdk version than desired)", best.Header.Config)
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// align4 rounds n up to the next multiple of 4, matching the alignment
+// every chunk (and the string/style data regions within a pool) is
+// padded to on disk.
+func align4(n int) int { return (n + 3) &^ 3 }
+
+func encodeUTF16Length(n int) []byte {
+	if n <= 0x7FFF {
+		buf := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buf, uint16(n))
+		return buf
+	}
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint16(buf[0:2], uint16(0x8000|((n>>16)&0x7FFF)))
+	binary.LittleEndian.PutUint16(buf[2:4], uint16(n&0xFFFF))
+	return buf
+}
+
+func encodeUTF8Length(n int) []byte {
+	if n <= 0x7F {
+		return []byte{byte(n)}
+	}
+	return []byte{byte(0x80 | ((n >> 8) & 0x7F)), byte(n & 0xFF)}
+}
+
+// encodeString renders Strings[i] back to its on-disk form: a UTF-16LE
+// string prefixed by its length and NUL-terminated, or (when UTF8_FLAG is
+// set) a UTF-8 string prefixed by its char count then its byte count.
+func (sp *ResStringPool) encodeString(i int) []byte {
+	str := sp.Strings[i]
+	var buf bytes.Buffer
+	if sp.Header.Flags&UTF8_FLAG == 0 {
+		units := utf16.Encode([]rune(str))
+		buf.Write(encodeUTF16Length(len(units)))
+		binary.Write(&buf, binary.LittleEndian, units)
+		buf.Write([]byte{0, 0})
+	} else {
+		data := []byte(str)
+		buf.Write(encodeUTF8Length(len([]rune(str))))
+		buf.Write(encodeUTF8Length(len(data)))
+		buf.Write(data)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// MarshalBinary encodes the pool back to its on-disk chunk form. It
+// honors UTF8_FLAG/SORTED_FLAG exactly as recorded in Header.Flags (it
+// never reorders Strings itself) and rebuilds the string/style offset
+// tables and data regions from scratch.
+func (sp *ResStringPool) MarshalBinary() ([]byte, error) {
+	stringOffsets := make([]uint32, len(sp.Strings))
+	var stringData bytes.Buffer
+	for i := range sp.Strings {
+		stringOffsets[i] = uint32(stringData.Len())
+		stringData.Write(sp.encodeString(i))
+	}
+	for stringData.Len()%4 != 0 {
+		stringData.WriteByte(0)
+	}
+
+	styleOffsets := make([]uint32, len(sp.Styles))
+	var styleData bytes.Buffer
+	for i, spans := range sp.Styles {
+		styleOffsets[i] = uint32(styleData.Len())
+		for _, span := range spans {
+			if err := binary.Write(&styleData, binary.LittleEndian, span); err != nil {
+				return nil, err
+			}
+		}
+		binary.Write(&styleData, binary.LittleEndian, uint32(END_SPAN))
+	}
+	if len(sp.Styles) > 0 {
+		// readStyleSpans always reads a full 12-byte ResStringPoolSpan
+		// before checking whether Name is the END_SPAN sentinel, so the
+		// last string's terminator needs two more trailing uint32s behind
+		// it (matching aapt's own output) or that read runs past the end
+		// of styleData.
+		binary.Write(&styleData, binary.LittleEndian, uint32(END_SPAN))
+		binary.Write(&styleData, binary.LittleEndian, uint32(END_SPAN))
+	}
+	for styleData.Len()%4 != 0 {
+		styleData.WriteByte(0)
+	}
+
+	const headerSize = 28
+	indexSize := 4 * (len(sp.Strings) + len(sp.Styles))
+	stringStart := uint32(headerSize + indexSize)
+	var stylesStart uint32
+	if len(sp.Styles) > 0 {
+		stylesStart = stringStart + uint32(stringData.Len())
+	}
+
+	header := sp.Header
+	header.Header.Type = RES_STRING_POOL_TYPE
+	header.Header.HeaderSize = headerSize
+	header.Header.Size = stringStart + uint32(stringData.Len()) + uint32(styleData.Len())
+	header.StringCount = uint32(len(sp.Strings))
+	header.StyleCount = uint32(len(sp.Styles))
+	header.StringStart = stringStart
+	header.StylesStart = stylesStart
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	binary.Write(&out, binary.LittleEndian, stringOffsets)
+	binary.Write(&out, binary.LittleEndian, styleOffsets)
+	out.Write(stringData.Bytes())
+	out.Write(styleData.Bytes())
+	return out.Bytes(), nil
+}
+
+// WriteTo writes the pool's marshalled form to w.
+func (sp *ResStringPool) WriteTo(w io.Writer) (int64, error) {
+	data, err := sp.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// MarshalBinary encodes a single table entry, scalar or complex, back to
+// its on-disk form (ResTableEntry+ResValue, or ResTableMapEntry+Map).
+func (e *TableEntry) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if e.Flags&FLAG_COMPLEX != 0 {
+		mapEntry := ResTableMapEntry{
+			Size:   16,
+			Flags:  uint16(e.Flags),
+			Key:    e.Key.Key,
+			Parent: e.Parent,
+			Count:  uint32(len(e.Map)),
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, mapEntry); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, e.Map); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	entry := ResTableEntry{Size: 8, Flags: uint16(e.Flags), Key: e.Key.Key}
+	if err := binary.Write(&buf, binary.LittleEndian, entry); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, *e.Value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalBinary encodes a TableType chunk: the fixed header, the
+// (variable-size) ResTableConfig, the entry offset index, and each
+// entry's bytes in turn.
+func (t *TableType) MarshalBinary() ([]byte, error) {
+	entryOffsets := make([]uint32, len(t.Entries))
+	var entryData bytes.Buffer
+	for i := range t.Entries {
+		if t.Entries[i].Key == nil {
+			entryOffsets[i] = 0xFFFFFFFF
+			continue
+		}
+		entryOffsets[i] = uint32(entryData.Len())
+		blob, err := t.Entries[i].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		entryData.Write(blob)
+	}
+
+	configSize := int(t.Header.Config.Size)
+	var cbuf bytes.Buffer
+	if err := binary.Write(&cbuf, binary.LittleEndian, t.Header.Config); err != nil {
+		return nil, err
+	}
+	configBytes := cbuf.Bytes()
+	switch {
+	case configSize <= len(configBytes):
+		configBytes = configBytes[:configSize]
+	default:
+		// The source config had fields newer than ResTableConfig knows
+		// about (e.g. locale variant); we only retain the ones
+		// decoded into the struct, so pad the rest with zeros to keep
+		// the chunk size faithful even though those trailing bytes won't
+		// match the original byte-for-byte.
+		configBytes = append(configBytes, make([]byte, configSize-len(configBytes))...)
+	}
+
+	const fixedSize = 8 + 1 + 1 + 2 + 4 + 4 // Header + Id + Res0 + Res1 + EntryCount + EntriesStart
+	headerSize := fixedSize + configSize
+	entriesStart := headerSize + 4*len(t.Entries)
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, ResChunkHeader{
+		Type:       RES_TABLE_TYPE_TYPE,
+		HeaderSize: uint16(headerSize),
+		Size:       uint32(align4(entriesStart + entryData.Len())),
+	}); err != nil {
+		return nil, err
+	}
+	binary.Write(&out, binary.LittleEndian, t.Header.Id)
+	binary.Write(&out, binary.LittleEndian, t.Header.Res0)
+	binary.Write(&out, binary.LittleEndian, t.Header.Res1)
+	binary.Write(&out, binary.LittleEndian, uint32(len(t.Entries)))
+	binary.Write(&out, binary.LittleEndian, uint32(entriesStart))
+	out.Write(configBytes)
+	binary.Write(&out, binary.LittleEndian, entryOffsets)
+	out.Write(entryData.Bytes())
+	for out.Len()%4 != 0 {
+		out.WriteByte(0)
+	}
+	return out.Bytes(), nil
+}
+
+// MarshalBinary encodes the package chunk itself: header, type/key string
+// pools, and each contained TableType. Type-spec chunks aren't retained
+// by the decoder (ReadTablePackage discards their flags today), so a
+// round-tripped package reproduces its types but not their original
+// RES_TABLE_TYPE_SPEC_TYPE siblings. (A sibling RES_TABLE_LIBRARY_TYPE
+// chunk, if any, is re-emitted by File.WriteTo, not here, mirroring how
+// readChunk reads it as a sibling rather than as part of the package.)
+func (p *TablePackage) MarshalBinary() ([]byte, error) {
+	typeStrings, err := p.TypeStrings.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	keyStrings, err := p.KeyStrings.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	const headerSize = 8 + 4 + 128*2 + 4 + 4 + 4 + 4 // Header+Id+Name+TypeStrings+LastPublicType+KeyStrings+LastPublicKey
+	typeStringsOff := headerSize
+	keyStringsOff := typeStringsOff + len(typeStrings)
+
+	var body bytes.Buffer
+	body.Write(typeStrings)
+	body.Write(keyStrings)
+	for _, tt := range p.TableTypes {
+		blob, err := tt.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		body.Write(blob)
+	}
+
+	header := p.Header
+	header.Header.Type = RES_TABLE_PACKAGE_TYPE
+	header.Header.HeaderSize = headerSize
+	header.Header.Size = uint32(headerSize + body.Len())
+	header.TypeStrings = uint32(typeStringsOff)
+	header.KeyStrings = uint32(keyStringsOff)
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// WriteTo re-encodes a decoded resource table back to its on-disk bytes,
+// chunk by chunk, in the order File originally read them.
+//
+// It only covers the RES_TABLE_TYPE root: NewFile flattens RES_XML_TYPE
+// straight into XMLBuffer rather than keeping a tree, so there's nothing
+// structured left for an XML File to re-marshal from yet. Round-tripping
+// manifests/layouts needs the XML decode path to build a tree instead of
+// a string buffer, which is a bigger follow-up than this one.
+//
+// Two smaller gaps remain even for the table it does cover: a package's
+// RES_TABLE_TYPE_SPEC_TYPE siblings aren't retained by the decoder, so
+// they aren't re-emitted, and a TableType whose original ResTableConfig
+// carried fields newer than this package knows about (see
+// TableType.MarshalBinary) has its trailing config bytes zeroed rather
+// than preserved.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if len(f.tablePackages) == 0 {
+		return 0, fmt.Errorf("androidbinary: File has no decoded resource table to marshal")
+	}
+
+	var body bytes.Buffer
+	if f.stringPool != nil {
+		poolBlob, err := f.stringPool.MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		body.Write(poolBlob)
+	}
+	for i := range f.tablePackages {
+		// Re-emit as a sibling chunk right before the package, matching
+		// the order readChunk expects to see it in (see pendingLibraries).
+		if libs := f.tablePackages[i].Libraries; len(libs) > 0 {
+			libBlob, err := marshalTableLibrary(libs)
+			if err != nil {
+				return 0, err
+			}
+			body.Write(libBlob)
+		}
+		blob, err := f.tablePackages[i].MarshalBinary()
+		if err != nil {
+			return 0, err
+		}
+		body.Write(blob)
+	}
+
+	const headerSize = 8 + 4 // ResTableHeader: Header + PackageCount
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, ResTableHeader{
+		Header: ResChunkHeader{
+			Type:       RES_TABLE_TYPE,
+			HeaderSize: headerSize,
+			Size:       uint32(headerSize + body.Len()),
+		},
+		PackageCount: uint32(len(f.tablePackages)),
+	}); err != nil {
+		return 0, err
+	}
+	out.Write(body.Bytes())
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
@@ -17,6 +17,16 @@ type File struct {
 	namespaces     map[ResStringPoolRef]ResStringPoolRef
 	XMLBuffer      bytes.Buffer
 	tablePackages  []TablePackage
+	// nextTablePackage is the index in tablePackages the next
+	// RES_TABLE_PACKAGE_TYPE chunk will be written to; it has to live on
+	// File rather than as a readChunk local since readTable drives many
+	// separate readChunk calls, one per sibling chunk.
+	nextTablePackage int
+	// pendingLibraries holds a RES_TABLE_LIBRARY_TYPE chunk's libraries
+	// until the RES_TABLE_PACKAGE_TYPE chunk they belong to is read: real
+	// aapt2 output emits the library chunk as a sibling *before* the
+	// package chunk it describes, not after.
+	pendingLibraries []LibraryEntry
 }
 
 const (
@@ -42,6 +52,7 @@ const (
 	RES_TABLE_PACKAGE_TYPE   = 0x0200
 	RES_TABLE_TYPE_TYPE      = 0x0201
 	RES_TABLE_TYPE_SPEC_TYPE = 0x0202
+	RES_TABLE_LIBRARY_TYPE   = 0x0203
 )
 
 type ResChunkHeader struct {
@@ -69,7 +80,30 @@ type ResStringPoolHeader struct {
 type ResStringPool struct {
 	Header  ResStringPoolHeader
 	Strings []string
-	Styles  []string
+	// Styles holds, for each styled string, the run of spans applied to
+	// it (e.g. bold/italic/underline markup embedded in a CDATA string).
+	// Styles[i] is nil for strings with no associated style.
+	Styles [][]ResStringPoolSpan
+}
+
+// END_SPAN terminates the span list for one styled string.
+const END_SPAN = 0xFFFFFFFF
+
+// ResStringPoolSpan is one span of a styled string: the style's name
+// (itself a reference into the same pool, e.g. "b", "i", "u" or
+// "font;color=#ff0000") and the inclusive character range it covers.
+type ResStringPoolSpan struct {
+	Name      ResStringPoolRef
+	FirstChar uint32
+	LastChar  uint32
+}
+
+// GetString returns the string at ref, or "" for NilResStringPoolRef.
+func (sp *ResStringPool) GetString(ref ResStringPoolRef) string {
+	if ref == NilResStringPoolRef {
+		return ""
+	}
+	return sp.Strings[int(ref)]
 }
 
 type ResXMLTreeNode struct {
@@ -120,6 +154,13 @@ const (
 	TYPE_INT_COLOR_RGB4  = 0x1f
 	TYPE_LAST_COLOR_INT  = 0x1f
 	TYPE_LAST_INT        = 0x1f
+
+	// Not part of the TYPE_FIRST_INT..TYPE_LAST_INT range: these only
+	// appear in table entries built by AGP for AARs/split APKs, and must
+	// be remapped through the owning package's dynamic reference table
+	// before they can be resolved.
+	TYPE_DYNAMIC_REFERENCE = 0x07
+	TYPE_DYNAMIC_ATTRIBUTE = 0x08
 )
 
 type ResValue struct {
@@ -154,6 +195,11 @@ type TablePackage struct {
 	TypeStrings *ResStringPool
 	KeyStrings  *ResStringPool
 	TableTypes  []*TableType
+	// Libraries lists the shared/static libraries this package was built
+	// against, decoded from a RES_TABLE_LIBRARY_TYPE chunk (a sibling before
+	// the package chunk, or one inside it). Entries whose package byte is
+	// dynamic (0x00, or 0x02 plus a position in this list) resolve through here.
+	Libraries []LibraryEntry
 }
 
 type ResTableType struct {
@@ -166,6 +212,10 @@ type ResTableType struct {
 	Config       ResTableConfig
 }
 
+// ResTableConfig has grown new fields with every Android release; Size
+// records how many bytes of it a given TableType actually stored on disk
+// (see ReadTableConfig), so fields beyond that are always zero rather
+// than garbage.
 type ResTableConfig struct {
 	Size   uint32
 	Imsi   uint32
@@ -178,6 +228,12 @@ type ResTableConfig struct {
 	ScreenSize   uint32
 	Version      uint32
 	ScreenConfig uint32
+	ScreenSizeDp uint32
+	// LocaleScript is the 4-letter ISO 15924 script subtag of a BCP-47
+	// locale qualifier (e.g. "Latn" in "b+sr+Latn+RS"), zero-padded.
+	// Bare "lang"/"lang-rREGION" qualifiers never set it.
+	LocaleScript  [4]uint8
+	ScreenConfig2 uint32 // screen round (bits 0-1), color mode, wide gamut/HDR
 }
 
 type TableType struct {
@@ -185,16 +241,64 @@ type TableType struct {
 	Entries []TableEntry
 }
 
+// FLAG_COMPLEX marks a ResTableEntry as a bag (map/style/array/plural)
+// rather than a single scalar ResValue.
+const FLAG_COMPLEX = 0x0001
+
 type ResTableEntry struct {
 	Size  uint16
 	Flags uint16
 	Key   ResStringPoolRef
 }
 
+// ResTableRef is a raw, unresolved resource id as stored on disk (package
+// byte | type byte | entry index), as opposed to the string form ResID
+// parses from XML attribute values.
+type ResTableRef uint32
+
+// ResTableMapEntry is the on-disk header of a complex (FLAG_COMPLEX)
+// entry: a ResTableEntry followed by a parent style reference and the
+// number of name/value pairs that make up the bag.
+type ResTableMapEntry struct {
+	Size   uint16
+	Flags  uint16
+	Key    ResStringPoolRef
+	Parent ResTableRef
+	Count  uint32
+}
+
+// ResTableMap is one name/value pair of a complex entry's bag. Name is
+// either an attribute resource id or one of the reserved ATTR_* sentinels
+// used by arrays and plurals.
+type ResTableMap struct {
+	Name  ResTableRef
+	Value ResValue
+}
+
+// Reserved attribute names used as ResTableMap.Name inside array, plural
+// and <declare-styleable> bags, mirroring AOSP's ResourceTypes.h.
+const (
+	ATTR_TYPE  = 0x01000000
+	ATTR_MIN   = 0x01000001
+	ATTR_MAX   = 0x01000002
+	ATTR_L10N  = 0x01000003
+	ATTR_OTHER = 0x01000004
+	ATTR_ZERO  = 0x01000005
+	ATTR_ONE   = 0x01000006
+	ATTR_TWO   = 0x01000007
+	ATTR_FEW   = 0x01000008
+	ATTR_MANY  = 0x01000009
+)
+
 type TableEntry struct {
 	Key   *ResTableEntry
 	Value *ResValue
 	Flags uint32
+
+	// Parent and Map are only populated when Flags&FLAG_COMPLEX != 0;
+	// Value is nil in that case.
+	Parent ResTableRef
+	Map    []ResTableMap
 }
 
 type ResTableTypeSpec struct {
@@ -220,7 +324,6 @@ func (f *File) readChunk(r io.ReaderAt, offset int64) (*ResChunkHeader, error) {
 	}
 
 	var err error
-	numTablePackages := 0
 	sr.Seek(0, os.SEEK_SET)
 	switch chunkHeader.Type {
 	case RES_TABLE_TYPE:
@@ -239,11 +342,35 @@ func (f *File) readChunk(r io.ReaderAt, offset int64) (*ResChunkHeader, error) {
 		err = f.ReadStartElement(sr)
 	case RES_XML_END_ELEMENT_TYPE:
 		err = f.ReadEndElement(sr)
+	case RES_XML_CDATA_TYPE:
+		err = f.ReadCDATA(sr)
 	case RES_TABLE_PACKAGE_TYPE:
 		var tablePackage *TablePackage
 		tablePackage, err = ReadTablePackage(sr)
-		f.tablePackages[numTablePackages] = *tablePackage
-		numTablePackages++
+		if err == nil {
+			if f.pendingLibraries != nil {
+				tablePackage.Libraries = f.pendingLibraries
+				f.pendingLibraries = nil
+			}
+			f.tablePackages[f.nextTablePackage] = *tablePackage
+			f.nextTablePackage++
+		}
+	case RES_TABLE_LIBRARY_TYPE:
+		var libs []LibraryEntry
+		libs, err = ReadTableLibrary(sr)
+		if err == nil {
+			if f.nextTablePackage < len(f.tablePackages) {
+				// The common aapt2 ordering: this library chunk precedes
+				// the package chunk it describes, so stash it until that
+				// RES_TABLE_PACKAGE_TYPE chunk arrives.
+				f.pendingLibraries = libs
+			} else if f.nextTablePackage > 0 {
+				// All packages are already read; fall back to attaching
+				// to the last one, in case some producer emits the
+				// library chunk after its package instead.
+				f.tablePackages[f.nextTablePackage-1].Libraries = libs
+			}
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -256,6 +383,8 @@ func (f *File) readTable(sr *io.SectionReader) error {
 	header := new(ResTableHeader)
 	binary.Read(sr, binary.LittleEndian, header)
 	f.tablePackages = make([]TablePackage, header.PackageCount)
+	f.nextTablePackage = 0
+	f.pendingLibraries = nil
 
 	offset := int64(header.Header.HeaderSize)
 	for offset < int64(header.Header.Size) {
@@ -285,10 +414,7 @@ func (f *File) readXML(sr *io.SectionReader) error {
 }
 
 func (f *File) GetString(ref ResStringPoolRef) string {
-	if ref == NilResStringPoolRef {
-		return ""
-	}
-	return f.stringPool.Strings[int(ref)]
+	return f.stringPool.GetString(ref)
 }
 
 func ReadStringPool(sr *io.SectionReader) (*ResStringPool, error) {
@@ -315,24 +441,36 @@ func ReadStringPool(sr *io.SectionReader) (*ResStringPool, error) {
 		sp.Strings[i] = str
 	}
 
-	sp.Styles = make([]string, sp.Header.StyleCount)
+	sp.Styles = make([][]ResStringPoolSpan, sp.Header.StyleCount)
 	for i, start := range styleStarts {
-		var str string
-		var err error
-		if (sp.Header.Flags & UTF8_FLAG) == 0 {
-			str, err = ReadUTF16(sr, int64(sp.Header.StylesStart+start))
-		} else {
-			str, err = ReadUTF8(sr, int64(sp.Header.StylesStart+start))
-		}
+		spans, err := readStyleSpans(sr, int64(sp.Header.StylesStart+start))
 		if err != nil {
 			return nil, err
 		}
-		sp.Styles[i] = str
+		sp.Styles[i] = spans
 	}
 
 	return sp, nil
 }
 
+// readStyleSpans reads the ResStringPoolSpan triples starting at offset,
+// stopping at the END_SPAN sentinel.
+func readStyleSpans(sr *io.SectionReader, offset int64) ([]ResStringPoolSpan, error) {
+	sr.Seek(offset, os.SEEK_SET)
+	var spans []ResStringPoolSpan
+	for {
+		var span ResStringPoolSpan
+		if err := binary.Read(sr, binary.LittleEndian, &span); err != nil {
+			return nil, err
+		}
+		if uint32(span.Name) == END_SPAN {
+			break
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
 func ReadUTF16(sr *io.SectionReader, offset int64) (string, error) {
 	// read lenth of string
 	var size int
@@ -359,20 +497,17 @@ func ReadUTF16(sr *io.SectionReader, offset int64) (string, error) {
 }
 
 func ReadUTF8(sr *io.SectionReader, offset int64) (string, error) {
-	// read lenth of string
-	var size int
-	var first, second uint8
 	sr.Seek(offset, os.SEEK_SET)
-	if err := binary.Read(sr, binary.LittleEndian, &first); err != nil {
+
+	// UTF-8 pool entries store the length twice: first the length in
+	// characters (which we don't need, since Go strings are byte-indexed),
+	// then the length in bytes that follows.
+	if _, err := readUTF8Length(sr); err != nil {
 		return "", err
 	}
-	if (first & 0x80) != 0 {
-		if err := binary.Read(sr, binary.LittleEndian, &second); err != nil {
-			return "", err
-		}
-		size = (int(first&0x7F) << 8) + int(second)
-	} else {
-		size = int(first)
+	size, err := readUTF8Length(sr)
+	if err != nil {
+		return "", err
 	}
 
 	buf := make([]uint8, size)
@@ -382,6 +517,23 @@ func ReadUTF8(sr *io.SectionReader, offset int64) (string, error) {
 	return string(buf), nil
 }
 
+// readUTF8Length reads one of the two length prefixes (char count, byte
+// count) used by UTF-8 string pool entries: one byte, or two if the
+// high bit is set.
+func readUTF8Length(sr *io.SectionReader) (int, error) {
+	var first, second uint8
+	if err := binary.Read(sr, binary.LittleEndian, &first); err != nil {
+		return 0, err
+	}
+	if (first & 0x80) == 0 {
+		return int(first), nil
+	}
+	if err := binary.Read(sr, binary.LittleEndian, &second); err != nil {
+		return 0, err
+	}
+	return (int(first&0x7F) << 8) + int(second), nil
+}
+
 func ReadResourceMap(sr *io.SectionReader) ([]uint32, error) {
 	header := new(ResChunkHeader)
 	binary.Read(sr, binary.LittleEndian, header)
@@ -453,25 +605,7 @@ func (f *File) ReadStartElement(sr *io.SectionReader) error {
 		if attr.RawValue != NilResStringPoolRef {
 			value = f.GetString(attr.RawValue)
 		} else {
-			data := attr.TypedValue.Data
-			switch attr.TypedValue.DataType {
-			case TYPE_NULL:
-				value = ""
-			case TYPE_REFERENCE:
-				value = fmt.Sprintf("@0x%08X", data)
-			case TYPE_INT_DEC:
-				value = fmt.Sprintf("%d", data)
-			case TYPE_INT_HEX:
-				value = fmt.Sprintf("0x%08X", data)
-			case TYPE_INT_BOOLEAN:
-				if data != 0 {
-					value = "true"
-				} else {
-					value = "false"
-				}
-			default:
-				value = fmt.Sprintf("@0x%08X", data)
-			}
+			value = attr.TypedValue.String(f.stringPool)
 		}
 
 		fmt.Fprintf(&f.XMLBuffer, " %s=\"", f.AddNamespace(attr.NS, attr.Name))
@@ -497,6 +631,37 @@ func (f *File) ReadEndElement(sr *io.SectionReader) error {
 	return nil
 }
 
+// ResXMLTreeCdataExt is the body of a RES_XML_CDATA_TYPE chunk: the raw
+// text, plus its typed form (used when the text is e.g. a pure number).
+type ResXMLTreeCdataExt struct {
+	Data      ResStringPoolRef
+	TypedData ResValue
+}
+
+// ReadCDATA decodes a RES_XML_CDATA_TYPE chunk and appends its escaped
+// text to XMLBuffer. Without this, text nodes between a start and end
+// element (e.g. <string name="foo">bar</string>) are silently dropped.
+func (f *File) ReadCDATA(sr *io.SectionReader) error {
+	header := new(ResXMLTreeNode)
+	if err := binary.Read(sr, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	sr.Seek(int64(header.Header.HeaderSize), os.SEEK_SET)
+	ext := new(ResXMLTreeCdataExt)
+	if err := binary.Read(sr, binary.LittleEndian, ext); err != nil {
+		return err
+	}
+
+	var text string
+	if ext.Data != NilResStringPoolRef {
+		text = f.GetString(ext.Data)
+	} else {
+		text = ext.TypedData.String(f.stringPool)
+	}
+	xml.Escape(&f.XMLBuffer, []byte(text))
+	return nil
+}
+
 func (f *File) AddNamespace(ns, name ResStringPoolRef) string {
 	if ns != NilResStringPoolRef {
 		prefix := f.GetString(f.namespaces[ns])
@@ -545,6 +710,8 @@ func ReadTablePackage(sr *io.SectionReader) (*TablePackage, error) {
 			tablePackage.TableTypes = append(tablePackage.TableTypes, tableType)
 		case RES_TABLE_TYPE_SPEC_TYPE:
 			_, err = ReadTableTypeSpec(chunkReader)
+		case RES_TABLE_LIBRARY_TYPE:
+			tablePackage.Libraries, err = ReadTableLibrary(chunkReader)
 		}
 		if err != nil {
 			return nil, err
@@ -556,10 +723,30 @@ func ReadTablePackage(sr *io.SectionReader) (*TablePackage, error) {
 }
 
 func ReadTableType(sr *io.SectionReader) (*TableType, error) {
-	header := new(ResTableType)
-	if err := binary.Read(sr, binary.LittleEndian, header); err != nil {
+	var fixed struct {
+		Header       ResChunkHeader
+		Id           uint8
+		Res0         uint8
+		Res1         uint16
+		EntryCount   uint32
+		EntriesStart uint32
+	}
+	if err := binary.Read(sr, binary.LittleEndian, &fixed); err != nil {
 		return nil, err
 	}
+	config, err := ReadTableConfig(sr)
+	if err != nil {
+		return nil, err
+	}
+	header := &ResTableType{
+		Header:       fixed.Header,
+		Id:           fixed.Id,
+		Res0:         fixed.Res0,
+		Res1:         fixed.Res1,
+		EntryCount:   fixed.EntryCount,
+		EntriesStart: fixed.EntriesStart,
+		Config:       *config,
+	}
 
 	entryIndexes := make([]uint32, header.EntryCount)
 	sr.Seek(int64(header.Header.HeaderSize), os.SEEK_SET)
@@ -576,6 +763,21 @@ func ReadTableType(sr *io.SectionReader) (*TableType, error) {
 		var key ResTableEntry
 		binary.Read(sr, binary.LittleEndian, &key)
 		entries[i].Key = &key
+		entries[i].Flags = uint32(key.Flags)
+
+		if key.Flags&FLAG_COMPLEX != 0 {
+			sr.Seek(int64(header.EntriesStart+index), os.SEEK_SET)
+			var mapEntry ResTableMapEntry
+			if err := binary.Read(sr, binary.LittleEndian, &mapEntry); err != nil {
+				return nil, err
+			}
+			entries[i].Parent = mapEntry.Parent
+			entries[i].Map = make([]ResTableMap, mapEntry.Count)
+			if err := binary.Read(sr, binary.LittleEndian, entries[i].Map); err != nil {
+				return nil, err
+			}
+			continue
+		}
 
 		var val ResValue
 		binary.Read(sr, binary.LittleEndian, &val)
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// dimensionUnits and fractionUnits are indexed by the low nibble (bits
+// 0-3) of a TYPE_DIMENSION/TYPE_FRACTION ResValue.Data, per AOSP's
+// Res_value::COMPLEX_UNIT_* / COMPLEX_UNIT_FRACTION_*.
+var dimensionUnits = [...]string{"px", "dp", "sp", "pt", "in", "mm"}
+var fractionUnits = [...]string{"%", "%p"}
+
+// radixScales is indexed by bits 4-5 of Data (COMPLEX_RADIX_*): the
+// fixed-point radix used to interpret the mantissa in bits 8-31. Scaled
+// to pair with a mantissa that's already been shifted down by 8 (see
+// complexToFloat), so radix 0 (23p0: all 23 usable bits are integer,
+// none fractional) is a no-op multiplier.
+var radixScales = [...]float64{
+	1.0 / (1 << 0),
+	1.0 / (1 << 7),
+	1.0 / (1 << 15),
+	1.0 / (1 << 23),
+}
+
+// complexToFloat decodes a COMPLEX_UNIT-style packed value: mantissa in
+// the top 24 bits, radix selector in bits 4-5.
+func complexToFloat(data uint32) float64 {
+	radix := (data >> 4) & 0x3
+	mantissa := int32(data&0xFFFFFF00) >> 8
+	return float64(mantissa) * radixScales[radix]
+}
+
+// String renders v using pool to resolve TYPE_STRING references,
+// following the same formatting AOSP uses when dumping resource values
+// (aapt dump / ResTable::Theme). pool may be nil, in which case
+// TYPE_STRING falls back to the raw reference form.
+func (v ResValue) String(pool *ResStringPool) string {
+	data := v.Data
+	switch v.DataType {
+	case TYPE_NULL:
+		return ""
+	case TYPE_REFERENCE, TYPE_DYNAMIC_REFERENCE:
+		return fmt.Sprintf("@0x%08X", data)
+	case TYPE_ATTRIBUTE, TYPE_DYNAMIC_ATTRIBUTE:
+		return fmt.Sprintf("?0x%08X", data)
+	case TYPE_STRING:
+		if pool != nil && int(data) < len(pool.Strings) {
+			return pool.GetString(ResStringPoolRef(data))
+		}
+		return fmt.Sprintf("@0x%08X", data)
+	case TYPE_FLOAT:
+		return fmt.Sprintf("%g", math.Float32frombits(data))
+	case TYPE_DIMENSION:
+		unit := dimensionUnits[0]
+		if idx := int(data & 0xF); idx < len(dimensionUnits) {
+			unit = dimensionUnits[idx]
+		}
+		return fmt.Sprintf("%g%s", complexToFloat(data), unit)
+	case TYPE_FRACTION:
+		unit := fractionUnits[0]
+		if idx := int(data & 0xF); idx < len(fractionUnits) {
+			unit = fractionUnits[idx]
+		}
+		return fmt.Sprintf("%g%s", complexToFloat(data)*100, unit)
+	case TYPE_INT_DEC:
+		return fmt.Sprintf("%d", data)
+	case TYPE_INT_HEX:
+		return fmt.Sprintf("0x%08X", data)
+	case TYPE_INT_BOOLEAN:
+		if data != 0 {
+			return "true"
+		}
+		return "false"
+	case TYPE_INT_COLOR_ARGB8:
+		return fmt.Sprintf("#%08X", data)
+	case TYPE_INT_COLOR_RGB8:
+		return fmt.Sprintf("#%06X", data&0xFFFFFF)
+	case TYPE_INT_COLOR_ARGB4:
+		return fmt.Sprintf("#%04X", data&0xFFFF)
+	case TYPE_INT_COLOR_RGB4:
+		return fmt.Sprintf("#%03X", data&0xFFF)
+	default:
+		return fmt.Sprintf("@0x%08X", data)
+	}
+}
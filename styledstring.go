@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// styledSpanTag maps a style span's name (as stored in the string pool —
+// "b", "i", "u", or "font;color=#ff0000"-style attribute lists) to the
+// HTML tag used to render it.
+func styledSpanTag(name string) string {
+	switch name {
+	case "b", "i", "u":
+		return name
+	}
+	if strings.HasPrefix(name, "font;") {
+		rest := strings.TrimPrefix(name, "font;")
+		return "font " + strings.Replace(rest, ";", " ", -1)
+	}
+	return name
+}
+
+// RenderStyledStringHTML renders Strings[i] as HTML, wrapping the ranges
+// covered by its style spans in the corresponding b/i/u/font tag. Text
+// itself is always escaped (the same way ReadCDATA escapes XML text), so a
+// string containing "&"/"<"/">" can't produce invalid or injectable HTML.
+func (sp *ResStringPool) RenderStyledStringHTML(i int) string {
+	str := sp.GetString(ResStringPoolRef(i))
+	if i >= len(sp.Styles) || len(sp.Styles[i]) == 0 {
+		var buf bytes.Buffer
+		xml.Escape(&buf, []byte(str))
+		return buf.String()
+	}
+
+	runes := []rune(str)
+	opens := make(map[int][]string)
+	closes := make(map[int][]string)
+	for _, span := range sp.Styles[i] {
+		tag := styledSpanTag(sp.GetString(span.Name))
+		opens[int(span.FirstChar)] = append(opens[int(span.FirstChar)], tag)
+		closes[int(span.LastChar)+1] = append(closes[int(span.LastChar)+1], tag)
+	}
+
+	var buf bytes.Buffer
+	for pos := 0; pos <= len(runes); pos++ {
+		for _, tag := range closes[pos] {
+			fmt.Fprintf(&buf, "</%s>", strings.SplitN(tag, " ", 2)[0])
+		}
+		if pos == len(runes) {
+			break
+		}
+		for _, tag := range opens[pos] {
+			fmt.Fprintf(&buf, "<%s>", tag)
+		}
+		xml.Escape(&buf, []byte(string(runes[pos])))
+	}
+	return buf.String()
+}
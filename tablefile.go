@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ResID is a resource identifier (package byte | type byte | 16-bit entry
+// index), the form XML attribute values spell as e.g. "@0x7f020000".
+type ResID uint32
+
+// Package returns the package byte (bits 24-31).
+func (id ResID) Package() uint8 { return uint8(id >> 24) }
+
+// Type returns the type byte (bits 16-23).
+func (id ResID) Type() uint8 { return uint8(id >> 16) }
+
+// Entry returns the entry index (bits 0-15).
+func (id ResID) Entry() uint16 { return uint16(id) }
+
+// String renders id the way it appears in binary XML attribute values.
+func (id ResID) String() string {
+	return fmt.Sprintf("@0x%08X", uint32(id))
+}
+
+// IsResID reports whether s is a resource reference, e.g. "@0x7f020000".
+func IsResID(s string) bool {
+	return strings.HasPrefix(s, "@0x") || strings.HasPrefix(s, "@0X")
+}
+
+// ParseResID parses a "@0x7f020000"-style reference into a ResID.
+func ParseResID(s string) (ResID, error) {
+	if !IsResID(s) {
+		return 0, fmt.Errorf("androidbinary: not a resource id: %q", s)
+	}
+	v, err := strconv.ParseUint(s[3:], 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	return ResID(v), nil
+}
+
+// TableFile wraps a decoded resource table (resources.arsc) for lookups by
+// ResID. It remaps dynamic package references (AGP assigns a package's own
+// AAR/split-APK dependencies a placeholder package id at compile time:
+// 0x00, or 0x02 plus the dependency's position in the RES_TABLE_LIBRARY_TYPE
+// list) through the owning package's Libraries before resolving.
+type TableFile struct {
+	file *File
+}
+
+// NewTableFile wraps f for GetResource lookups.
+func NewTableFile(f *File) *TableFile {
+	return &TableFile{file: f}
+}
+
+// GetResource resolves id against config (picking the best match among the
+// candidate TableTypes via bestMatch) and returns a typed Go value: bool,
+// uint32, string, or float32, following TYPE_REFERENCE/TYPE_DYNAMIC_REFERENCE
+// chains until they land on a concrete value.
+func (t *TableFile) GetResource(id ResID, config *ResTableConfig) (interface{}, error) {
+	entry, err := t.resolveEntry(id, config)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Value == nil {
+		return nil, fmt.Errorf("androidbinary: no resource found for %s", id)
+	}
+	return t.resolveValue(entry.Value, config)
+}
+
+// resolveEntry remaps id, picks the best-matching TableType for config via
+// bestMatch, and returns the raw TableEntry at id's entry index. It's the
+// lookup GetResource, GetStyle, GetArray, and GetPlural all share; callers
+// that want a bag (FLAG_COMPLEX) interpret entry.Parent/Map themselves,
+// since what those mean depends on which of the three bag shapes it is.
+func (t *TableFile) resolveEntry(id ResID, config *ResTableConfig) (*TableEntry, error) {
+	id = t.remapIfDynamic(id)
+
+	pkg, err := t.resolvePackage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*TableType
+	for _, tt := range pkg.TableTypes {
+		if tt.Header.Id == id.Type() {
+			candidates = append(candidates, tt)
+		}
+	}
+	tt := bestMatch(candidates, config)
+	if tt == nil {
+		return nil, fmt.Errorf("androidbinary: no resource found for %s", id)
+	}
+
+	idx := int(id.Entry())
+	if idx >= len(tt.Entries) {
+		return nil, fmt.Errorf("androidbinary: no resource found for %s", id)
+	}
+	return &tt.Entries[idx], nil
+}
+
+// GetStyle resolves id as a style: a FLAG_COMPLEX entry whose Map holds
+// attribute id/value pairs and whose Parent, if non-zero, names another
+// style to inherit from. The result holds the parent's attributes first,
+// with id's own Map entries overriding same-named parent attributes,
+// matching AOSP's style inheritance (TypedArray.obtainStyledAttributes
+// applies the theme/parent chain the same way, furthest ancestor first).
+func (t *TableFile) GetStyle(id ResID, config *ResTableConfig) ([]ResTableMap, error) {
+	entry, err := t.resolveEntry(id, config)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Flags&FLAG_COMPLEX == 0 {
+		return nil, fmt.Errorf("androidbinary: %s is not a style", id)
+	}
+
+	var resolved []ResTableMap
+	if entry.Parent != 0 {
+		resolved, err = t.GetStyle(ResID(entry.Parent), config)
+		if err != nil {
+			return nil, fmt.Errorf("androidbinary: resolving parent style of %s: %w", id, err)
+		}
+	}
+	for _, m := range entry.Map {
+		replaced := false
+		for i, r := range resolved {
+			if r.Name == m.Name {
+				resolved[i] = m
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			resolved = append(resolved, m)
+		}
+	}
+	return resolved, nil
+}
+
+// GetArray resolves id as an array bag (string-array/integer-array/array,
+// and a <declare-styleable>'s own attribute list, which aapt also compiles
+// to an array bag of attr ResTableRefs) and returns its items in
+// declaration order, each resolved to a typed Go value the same way
+// GetResource resolves a scalar entry.
+//
+// For a <declare-styleable> specifically, this only gets as far as AOSP
+// itself does from resources.arsc alone: the array of attr ResIDs that
+// make up the styleable. Recovering each attr's name (R.styleable.Foo_bar)
+// needs the compile-time R.java/aapt symbol table, which isn't part of
+// resources.arsc and isn't decoded by this package.
+func (t *TableFile) GetArray(id ResID, config *ResTableConfig) ([]interface{}, error) {
+	entry, err := t.resolveEntry(id, config)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Flags&FLAG_COMPLEX == 0 {
+		return nil, fmt.Errorf("androidbinary: %s is not an array", id)
+	}
+	out := make([]interface{}, len(entry.Map))
+	for i, m := range entry.Map {
+		v, err := t.resolveValue(&m.Value, config)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// pluralCategories maps the ATTR_* sentinels a plural bag's Map uses to
+// their CLDR plural category name.
+var pluralCategories = map[ResTableRef]string{
+	ATTR_ZERO: "zero", ATTR_ONE: "one", ATTR_TWO: "two",
+	ATTR_FEW: "few", ATTR_MANY: "many", ATTR_OTHER: "other",
+}
+
+// GetPlural resolves id as a plural bag and returns its defined quantity
+// strings keyed by CLDR category name ("zero", "one", "two", "few",
+// "many", "other"); categories the bag doesn't define for this locale are
+// absent from the result rather than zero-valued.
+func (t *TableFile) GetPlural(id ResID, config *ResTableConfig) (map[string]string, error) {
+	entry, err := t.resolveEntry(id, config)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Flags&FLAG_COMPLEX == 0 {
+		return nil, fmt.Errorf("androidbinary: %s is not a plural", id)
+	}
+	out := make(map[string]string, len(entry.Map))
+	for _, m := range entry.Map {
+		category, ok := pluralCategories[m.Name]
+		if !ok {
+			continue
+		}
+		v, err := t.resolveValue(&m.Value, config)
+		if err != nil {
+			return nil, err
+		}
+		s, _ := v.(string)
+		out[category] = s
+	}
+	return out, nil
+}
+
+// resolvePackage finds the loaded TablePackage whose Header.Id matches
+// id's package byte.
+func (t *TableFile) resolvePackage(id ResID) (*TablePackage, error) {
+	pkgID := id.Package()
+	for i := range t.file.tablePackages {
+		if uint8(t.file.tablePackages[i].Header.Id) == pkgID {
+			return &t.file.tablePackages[i], nil
+		}
+	}
+	return nil, fmt.Errorf("androidbinary: no package loaded for id 0x%02X", pkgID)
+}
+
+// remapIfDynamic rewrites id's package byte when it's a placeholder AGP
+// assigned at build time for a library dependency (0x00, or 0x02 plus the
+// library's position in some package's Libraries list) to that library's
+// real, loaded package id. ids that already match a loaded package, or that
+// match no known library, are returned unchanged.
+func (t *TableFile) remapIfDynamic(id ResID) ResID {
+	pkgID := id.Package()
+	if pkgID != 0 {
+		for i := range t.file.tablePackages {
+			if uint8(t.file.tablePackages[i].Header.Id) == pkgID {
+				return id
+			}
+		}
+	}
+	for i := range t.file.tablePackages {
+		for j, lib := range t.file.tablePackages[i].Libraries {
+			if pkgID == 0 || pkgID == uint8(0x02+j) {
+				return ResID(uint32(lib.PackageID)<<24 | uint32(id)&0x00FFFFFF)
+			}
+		}
+	}
+	return id
+}
+
+// resolveValue converts a raw ResValue to the typed Go value Bool/Int32/
+// Float32/Dimension/Color expect. TYPE_REFERENCE and TYPE_DYNAMIC_REFERENCE
+// both recurse into GetResource, which is where the dynamic remap happens;
+// TYPE_DYNAMIC_ATTRIBUTE is remapped the same way but, like TYPE_ATTRIBUTE,
+// is returned as a raw id rather than resolved further.
+func (t *TableFile) resolveValue(v *ResValue, config *ResTableConfig) (interface{}, error) {
+	switch v.DataType {
+	case TYPE_REFERENCE, TYPE_DYNAMIC_REFERENCE:
+		return t.GetResource(ResID(v.Data), config)
+	case TYPE_ATTRIBUTE:
+		return uint32(v.Data), nil
+	case TYPE_DYNAMIC_ATTRIBUTE:
+		return uint32(t.remapIfDynamic(ResID(v.Data))), nil
+	case TYPE_STRING:
+		if t.file.stringPool == nil {
+			return "", nil
+		}
+		return t.file.stringPool.GetString(ResStringPoolRef(v.Data)), nil
+	case TYPE_FLOAT:
+		return math.Float32frombits(v.Data), nil
+	case TYPE_INT_BOOLEAN:
+		return v.Data != 0, nil
+	case TYPE_INT_DEC, TYPE_INT_HEX:
+		return v.Data, nil
+	case TYPE_DIMENSION, TYPE_FRACTION,
+		TYPE_INT_COLOR_ARGB8, TYPE_INT_COLOR_RGB8, TYPE_INT_COLOR_ARGB4, TYPE_INT_COLOR_RGB4:
+		return v.String(t.file.stringPool), nil
+	default:
+		return uint32(v.Data), nil
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResID(t *testing.T) {
+	id, err := ParseResID("@0x7f020000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Package() != 0x7f || id.Type() != 0x02 || id.Entry() != 0x0000 {
+		t.Errorf("got package=0x%02x type=0x%02x entry=0x%04x", id.Package(), id.Type(), id.Entry())
+	}
+	if !IsResID("@0x7f020000") || IsResID("16dp") {
+		t.Errorf("IsResID misclassified a value")
+	}
+}
+
+// TestTableFileDynamicReferenceRemap covers the case the original bug
+// report flagged: a ResID whose package byte is a placeholder AGP assigns
+// at compile time (0x02, the first declared library) must resolve against
+// the library's real, loaded package id (0x10 here), not be looked up
+// directly under 0x02.
+func TestTableFileDynamicReferenceRemap(t *testing.T) {
+	appPkg := TablePackage{
+		Header:    ResTablePackage{Id: 0x7f},
+		Libraries: []LibraryEntry{{PackageID: 0x10, PackageName: "com.example.lib"}},
+	}
+	libPkg := TablePackage{
+		Header: ResTablePackage{Id: 0x10},
+		TableTypes: []*TableType{newTableType(1, []TableEntry{
+			{Key: &ResTableEntry{Key: 0}, Value: &ResValue{DataType: TYPE_INT_DEC, Data: 99}},
+		})},
+	}
+	f := &File{tablePackages: []TablePackage{appPkg, libPkg}}
+	table := NewTableFile(f)
+
+	value, err := table.GetResource(ResID(0x02010000), nil)
+	if err != nil {
+		t.Fatalf("GetResource: %v", err)
+	}
+	if value != uint32(99) {
+		t.Errorf("GetResource = %v, want 99", value)
+	}
+}
+
+// TestTableFileGetStyle covers the parent-chain inheritance a style bag
+// needs: a child style's own attribute overrides its parent's, and an
+// attribute the child doesn't set is inherited unchanged.
+func TestTableFileGetStyle(t *testing.T) {
+	pkg := TablePackage{
+		Header: ResTablePackage{Id: 0x7f},
+		TableTypes: []*TableType{newTableType(1, []TableEntry{
+			{ // entry 0: parent style
+				Key:   &ResTableEntry{Key: 0},
+				Flags: FLAG_COMPLEX,
+				Map: []ResTableMap{
+					{Name: 0x7f010001, Value: ResValue{DataType: TYPE_INT_DEC, Data: 1}},
+					{Name: 0x7f010002, Value: ResValue{DataType: TYPE_INT_DEC, Data: 2}},
+				},
+			},
+			{ // entry 1: child style, overrides attr 0x7f010001, inherits 0x7f010002
+				Key:    &ResTableEntry{Key: 1},
+				Flags:  FLAG_COMPLEX,
+				Parent: ResTableRef(0x7f010000),
+				Map: []ResTableMap{
+					{Name: 0x7f010001, Value: ResValue{DataType: TYPE_INT_DEC, Data: 99}},
+				},
+			},
+		})},
+	}
+	f := &File{tablePackages: []TablePackage{pkg}}
+	table := NewTableFile(f)
+
+	got, err := table.GetStyle(ResID(0x7f010001), nil)
+	if err != nil {
+		t.Fatalf("GetStyle: %v", err)
+	}
+	want := map[ResTableRef]uint32{0x7f010001: 99, 0x7f010002: 2}
+	if len(got) != len(want) {
+		t.Fatalf("GetStyle = %+v, want %d attrs", got, len(want))
+	}
+	for _, m := range got {
+		if m.Value.Data != want[m.Name] {
+			t.Errorf("attr 0x%x = %d, want %d", m.Name, m.Value.Data, want[m.Name])
+		}
+	}
+}
+
+func TestTableFileGetArray(t *testing.T) {
+	pkg := TablePackage{
+		Header: ResTablePackage{Id: 0x7f},
+		TableTypes: []*TableType{newTableType(1, []TableEntry{
+			{
+				Key:   &ResTableEntry{Key: 0},
+				Flags: FLAG_COMPLEX,
+				Map: []ResTableMap{
+					{Value: ResValue{DataType: TYPE_INT_DEC, Data: 1}},
+					{Value: ResValue{DataType: TYPE_INT_DEC, Data: 2}},
+					{Value: ResValue{DataType: TYPE_INT_DEC, Data: 3}},
+				},
+			},
+		})},
+	}
+	f := &File{tablePackages: []TablePackage{pkg}}
+	table := NewTableFile(f)
+
+	got, err := table.GetArray(ResID(0x7f010000), nil)
+	if err != nil {
+		t.Fatalf("GetArray: %v", err)
+	}
+	want := []interface{}{uint32(1), uint32(2), uint32(3)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetArray = %+v, want %+v", got, want)
+	}
+}
+
+func TestTableFileGetPlural(t *testing.T) {
+	pkg := TablePackage{
+		Header:     ResTablePackage{Id: 0x7f},
+		KeyStrings: &ResStringPool{},
+	}
+	sp := &ResStringPool{Strings: []string{"one item", "%d items"}}
+	pkg.TableTypes = []*TableType{newTableType(1, []TableEntry{
+		{
+			Key:   &ResTableEntry{Key: 0},
+			Flags: FLAG_COMPLEX,
+			Map: []ResTableMap{
+				{Name: ATTR_ONE, Value: ResValue{DataType: TYPE_STRING, Data: 0}},
+				{Name: ATTR_OTHER, Value: ResValue{DataType: TYPE_STRING, Data: 1}},
+			},
+		},
+	})}
+	f := &File{tablePackages: []TablePackage{pkg}, stringPool: sp}
+	table := NewTableFile(f)
+
+	got, err := table.GetPlural(ResID(0x7f010000), nil)
+	if err != nil {
+		t.Fatalf("GetPlural: %v", err)
+	}
+	want := map[string]string{"one": "one item", "other": "%d items"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetPlural = %+v, want %+v", got, want)
+	}
+	if _, ok := got["many"]; ok {
+		t.Errorf("GetPlural set an undefined category %q", "many")
+	}
+}
+
+func TestTableFileDynamicReferenceChain(t *testing.T) {
+	appPkg := TablePackage{
+		Header:    ResTablePackage{Id: 0x7f},
+		Libraries: []LibraryEntry{{PackageID: 0x10, PackageName: "com.example.lib"}},
+		TableTypes: []*TableType{newTableType(1, []TableEntry{
+			{Key: &ResTableEntry{Key: 0}, Value: &ResValue{DataType: TYPE_DYNAMIC_REFERENCE, Data: 0x02010000}},
+		})},
+	}
+	libPkg := TablePackage{
+		Header: ResTablePackage{Id: 0x10},
+		TableTypes: []*TableType{newTableType(1, []TableEntry{
+			{Key: &ResTableEntry{Key: 0}, Value: &ResValue{DataType: TYPE_INT_DEC, Data: 7}},
+		})},
+	}
+	f := &File{tablePackages: []TablePackage{appPkg, libPkg}}
+	table := NewTableFile(f)
+
+	value, err := table.GetResource(ResID(0x7f010000), nil)
+	if err != nil {
+		t.Fatalf("GetResource: %v", err)
+	}
+	if value != uint32(7) {
+		t.Errorf("GetResource = %v, want 7 (dynamic reference chain not followed)", value)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+// ResTableLibraryHeader is the header of a RES_TABLE_LIBRARY_TYPE chunk,
+// declaring the shared/static libraries a package was linked against.
+type ResTableLibraryHeader struct {
+	Header ResChunkHeader
+	Count  uint32
+}
+
+// resTableLibraryEntry is the on-disk form of one library dependency.
+type resTableLibraryEntry struct {
+	PackageID   uint32
+	PackageName [128]uint16
+}
+
+// LibraryEntry is a decoded library dependency: the package id it was
+// compiled with, and its fully-qualified package name.
+type LibraryEntry struct {
+	PackageID   uint32
+	PackageName string
+}
+
+// ReadTableLibrary decodes a RES_TABLE_LIBRARY_TYPE chunk. AGP assigns
+// libraries a placeholder package id at compile time (0x00, or 0x02 plus
+// position in this list); TableFile.GetResource remaps a ResID carrying
+// one of those placeholder ids to the entry's real PackageID on each lookup.
+func ReadTableLibrary(sr *io.SectionReader) ([]LibraryEntry, error) {
+	header := new(ResTableLibraryHeader)
+	if err := binary.Read(sr, binary.LittleEndian, header); err != nil {
+		return nil, err
+	}
+
+	sr.Seek(int64(header.Header.HeaderSize), os.SEEK_SET)
+	entries := make([]LibraryEntry, header.Count)
+	for i := range entries {
+		var raw resTableLibraryEntry
+		if err := binary.Read(sr, binary.LittleEndian, &raw); err != nil {
+			return nil, err
+		}
+		entries[i] = LibraryEntry{
+			PackageID:   raw.PackageID,
+			PackageName: utf16NulString(raw.PackageName[:]),
+		}
+	}
+	return entries, nil
+}
+
+// utf16NulString decodes a NUL-terminated, fixed-width UTF-16 buffer such
+// as ResTablePackage.Name / resTableLibraryEntry.PackageName.
+func utf16NulString(buf []uint16) string {
+	n := 0
+	for n < len(buf) && buf[n] != 0 {
+		n++
+	}
+	return string(utf16.Decode(buf[:n]))
+}
+
+// marshalTableLibrary encodes libs back to a RES_TABLE_LIBRARY_TYPE chunk,
+// the counterpart to ReadTableLibrary.
+func marshalTableLibrary(libs []LibraryEntry) ([]byte, error) {
+	const headerSize = 8 + 4 // ResTableLibraryHeader: Header + Count
+	var body bytes.Buffer
+	for _, lib := range libs {
+		var raw resTableLibraryEntry
+		raw.PackageID = lib.PackageID
+		copy(raw.PackageName[:], utf16.Encode([]rune(lib.PackageName)))
+		if err := binary.Write(&body, binary.LittleEndian, raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	if err := binary.Write(&out, binary.LittleEndian, ResTableLibraryHeader{
+		Header: ResChunkHeader{
+			Type:       RES_TABLE_LIBRARY_TYPE,
+			HeaderSize: headerSize,
+			Size:       uint32(headerSize + body.Len()),
+		},
+		Count: uint32(len(libs)),
+	}); err != nil {
+		return nil, err
+	}
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -1,4 +1,4 @@
-package androidbinary
+package main
 
 import (
 	"encoding/xml"
@@ -210,3 +210,234 @@ func (v Int32) MustInt32() int32 {
 	}
 	return ret
 }
+
+// Float32 is a floating-point value in XML file.
+// It may be an immediate value or a reference.
+type Float32 struct {
+	value  string
+	table  *TableFile
+	config *ResTableConfig
+}
+
+// WithTableFile ties TableFile to the Float32.
+func (v Float32) WithTableFile(table *TableFile) Float32 {
+	return Float32{
+		value:  v.value,
+		table:  table,
+		config: v.config,
+	}
+}
+
+// WithResTableConfig ties ResTableConfig to the Float32.
+func (v Float32) WithResTableConfig(config *ResTableConfig) Float32 {
+	return Float32{
+		value:  v.value,
+		table:  v.table,
+		config: config,
+	}
+}
+
+func (v *Float32) inject(table *TableFile, config *ResTableConfig) {
+	v.table = table
+	v.config = config
+}
+
+// SetFloat32 sets a floating-point value.
+func (v *Float32) SetFloat32(value float32) {
+	v.value = strconv.FormatFloat(float64(value), 'g', -1, 32)
+}
+
+// SetResID sets a floating-point value with the resource id.
+func (v *Float32) SetResID(resID ResID) {
+	v.value = resID.String()
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (v *Float32) UnmarshalXMLAttr(attr xml.Attr) error {
+	v.value = attr.Value
+	return nil
+}
+
+// Float32 returns the floating-point value.
+// It resolves the reference if needed.
+func (v Float32) Float32() (float32, error) {
+	if v.value == "" {
+		return 0, nil
+	}
+	if !IsResID(v.value) {
+		f, err := strconv.ParseFloat(v.value, 32)
+		return float32(f), err
+	}
+	id, err := ParseResID(v.value)
+	if err != nil {
+		return 0, err
+	}
+	value, err := v.table.GetResource(id, v.config)
+	if err != nil {
+		return 0, err
+	}
+	ret, ok := value.(float32)
+	if !ok {
+		return 0, fmt.Errorf("invalid type: %T", value)
+	}
+	return ret, nil
+}
+
+// MustFloat32 is same as Float32, but it panics if it fails to parse the value.
+func (v Float32) MustFloat32() float32 {
+	ret, err := v.Float32()
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Dimension is a dimension/fraction value (e.g. "16dp", "50%") in XML file.
+// It may be an immediate value or a reference.
+type Dimension struct {
+	value  string
+	table  *TableFile
+	config *ResTableConfig
+}
+
+// WithTableFile ties TableFile to the Dimension.
+func (v Dimension) WithTableFile(table *TableFile) Dimension {
+	return Dimension{
+		value:  v.value,
+		table:  table,
+		config: v.config,
+	}
+}
+
+// WithResTableConfig ties ResTableConfig to the Dimension.
+func (v Dimension) WithResTableConfig(config *ResTableConfig) Dimension {
+	return Dimension{
+		value:  v.value,
+		table:  v.table,
+		config: config,
+	}
+}
+
+func (v *Dimension) inject(table *TableFile, config *ResTableConfig) {
+	v.table = table
+	v.config = config
+}
+
+// SetResID sets a dimension value with the resource id.
+func (v *Dimension) SetResID(resID ResID) {
+	v.value = resID.String()
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (v *Dimension) UnmarshalXMLAttr(attr xml.Attr) error {
+	v.value = attr.Value
+	return nil
+}
+
+// Dimension returns the formatted dimension string (e.g. "16dp"),
+// resolving the reference if needed.
+func (v Dimension) Dimension() (string, error) {
+	if v.value == "" {
+		return "", nil
+	}
+	if !IsResID(v.value) {
+		return v.value, nil
+	}
+	id, err := ParseResID(v.value)
+	if err != nil {
+		return "", err
+	}
+	value, err := v.table.GetResource(id, v.config)
+	if err != nil {
+		return "", err
+	}
+	ret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid type: %T", value)
+	}
+	return ret, nil
+}
+
+// MustDimension is same as Dimension, but it panics if it fails to parse the value.
+func (v Dimension) MustDimension() string {
+	ret, err := v.Dimension()
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Color is an ARGB color value (e.g. "#AARRGGBB") in XML file.
+// It may be an immediate value or a reference.
+type Color struct {
+	value  string
+	table  *TableFile
+	config *ResTableConfig
+}
+
+// WithTableFile ties TableFile to the Color.
+func (v Color) WithTableFile(table *TableFile) Color {
+	return Color{
+		value:  v.value,
+		table:  table,
+		config: v.config,
+	}
+}
+
+// WithResTableConfig ties ResTableConfig to the Color.
+func (v Color) WithResTableConfig(config *ResTableConfig) Color {
+	return Color{
+		value:  v.value,
+		table:  v.table,
+		config: config,
+	}
+}
+
+func (v *Color) inject(table *TableFile, config *ResTableConfig) {
+	v.table = table
+	v.config = config
+}
+
+// SetResID sets a color value with the resource id.
+func (v *Color) SetResID(resID ResID) {
+	v.value = resID.String()
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (v *Color) UnmarshalXMLAttr(attr xml.Attr) error {
+	v.value = attr.Value
+	return nil
+}
+
+// Color returns the formatted color string (e.g. "#AARRGGBB"),
+// resolving the reference if needed.
+func (v Color) Color() (string, error) {
+	if v.value == "" {
+		return "", nil
+	}
+	if !IsResID(v.value) {
+		return v.value, nil
+	}
+	id, err := ParseResID(v.value)
+	if err != nil {
+		return "", err
+	}
+	value, err := v.table.GetResource(id, v.config)
+	if err != nil {
+		return "", err
+	}
+	ret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid type: %T", value)
+	}
+	return ret, nil
+}
+
+// MustColor is same as Color, but it panics if it fails to parse the value.
+func (v Color) MustColor() string {
+	ret, err := v.Color()
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}